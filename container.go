@@ -0,0 +1,178 @@
+//go:build linux
+
+package conntracer
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/cilium/ebpf"
+)
+
+// ContainerInfo is what a ContainerResolver resolves a cgroup id to.
+type ContainerInfo struct {
+	ContainerID  string
+	PodName      string
+	PodNamespace string
+}
+
+// ContainerResolver maps a cgroup id, as reported by the BPF programs
+// via bpf_get_current_cgroup_id(), to the container/pod that owns it.
+// Implementations talk to a specific container runtime; see
+// NewContainerdResolver, NewCRIOResolver and NewDockerResolver.
+type ContainerResolver interface {
+	// Resolve looks up the container owning cgroupID. It returns
+	// (nil, nil) when cgroupID isn't known to the runtime, e.g. for
+	// processes running directly on the host.
+	Resolve(cgroupID uint64) (*ContainerInfo, error)
+}
+
+// defaultContainerCacheTTL bounds how long a resolved ContainerInfo is
+// reused before Container asks the resolver again. DumpFlows runs on
+// every polling tick, so without a cache every flow would otherwise
+// hit the container runtime's API on the hot path.
+const defaultContainerCacheTTL = 30 * time.Second
+
+// cachedContainerInfo wraps a resolver with a TTL cache keyed by cgroup
+// id, so pollFlows doesn't hammer the configured ContainerResolver.
+type cachedContainerInfo struct {
+	resolver ContainerResolver
+	ttl      time.Duration
+
+	mu      sync.Mutex
+	entries map[uint64]containerCacheEntry
+}
+
+type containerCacheEntry struct {
+	info      *ContainerInfo
+	expiresAt time.Time
+}
+
+func newCachedContainerInfo(resolver ContainerResolver, ttl time.Duration) *cachedContainerInfo {
+	if ttl <= 0 {
+		ttl = defaultContainerCacheTTL
+	}
+	return &cachedContainerInfo{
+		resolver: resolver,
+		ttl:      ttl,
+		entries:  make(map[uint64]containerCacheEntry),
+	}
+}
+
+// resolve returns the ContainerInfo for cgroupID, consulting the
+// underlying ContainerResolver only when the cache entry is missing or
+// has expired.
+func (c *cachedContainerInfo) resolve(cgroupID uint64) (*ContainerInfo, error) {
+	now := time.Now()
+
+	c.mu.Lock()
+	entry, ok := c.entries[cgroupID]
+	c.mu.Unlock()
+	if ok && now.Before(entry.expiresAt) {
+		return entry.info, nil
+	}
+
+	info, err := c.resolver.Resolve(cgroupID)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.entries[cgroupID] = containerCacheEntry{info: info, expiresAt: now.Add(c.ttl)}
+	c.mu.Unlock()
+
+	return info, nil
+}
+
+// defaultCgroupRoot is where Linux distributions mount the unified
+// (v2) cgroup hierarchy. Each container runtime creates its containers
+// under a runtime-specific subtree of this root.
+const defaultCgroupRoot = "/sys/fs/cgroup"
+
+// cgroupPathForID walks the cgroupfs looking for the directory whose
+// inode number equals cgroupID, since bpf_get_current_cgroup_id()
+// returns the kernfs node id, which is the cgroup v2 directory's inode
+// on every kernel that implements cgroup ids this way. Runtime-specific
+// resolvers use the returned path to pull a container id out of it.
+func cgroupPathForID(cgroupID uint64) (string, error) {
+	var found string
+	err := filepath.WalkDir(defaultCgroupRoot, func(path string, d os.DirEntry, err error) error {
+		if err != nil || found != "" || !d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		stat, ok := info.Sys().(*syscall.Stat_t)
+		if !ok {
+			return nil
+		}
+		if stat.Ino == cgroupID {
+			found = path
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to walk %s: %w", defaultCgroupRoot, err)
+	}
+	if found == "" {
+		return "", nil
+	}
+	return found, nil
+}
+
+// cgroupExcludeMarker is the value stored for every excluded cgroup id
+// in the cgroup_exclude BPF map; only its presence is checked.
+const cgroupExcludeMarker uint8 = 1
+
+// excludeCgroups resolves each cgroupfs path in paths to its cgroup id
+// (the directory's inode number, matching bpf_get_current_cgroup_id())
+// and pushes it into the cgroup_exclude map so the BPF programs drop
+// traffic from it before it ever reaches the flows map.
+func excludeCgroups(m *ebpf.Map, paths []string) error {
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return fmt.Errorf("failed to stat cgroup path %s: %w", path, err)
+		}
+		stat, ok := info.Sys().(*syscall.Stat_t)
+		if !ok {
+			return fmt.Errorf("unsupported platform: cannot read inode of %s", path)
+		}
+		if err := m.Put(stat.Ino, cgroupExcludeMarker); err != nil {
+			return fmt.Errorf("failed to exclude cgroup %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// populateContainerInfo enriches each flow in place from resolver,
+// leaving the container fields zero-valued when resolver is nil, the
+// cgroup id isn't known to it, or resolution fails. Resolution is
+// best-effort: a resolver error for one flow (e.g. a transient failure
+// talking to the container runtime's API) only leaves that flow
+// unenriched, it never discards the rest of the batch.
+func populateContainerInfo(flows []*Flow, resolver *cachedContainerInfo) {
+	if resolver == nil {
+		return
+	}
+	for _, flow := range flows {
+		info, err := resolver.resolve(flow.CgroupID)
+		if err != nil {
+			log.Printf("conntracer: failed to resolve container info for cgroup %d: %v", flow.CgroupID, err)
+			continue
+		}
+		if info == nil {
+			continue
+		}
+		flow.ContainerID = info.ContainerID
+		flow.PodName = info.PodName
+		flow.PodNamespace = info.PodNamespace
+	}
+}
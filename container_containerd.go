@@ -0,0 +1,25 @@
+//go:build linux
+
+package conntracer
+
+import "regexp"
+
+// defaultContainerdEndpoint is where containerd listens for CRI
+// requests on most distributions (Docker Desktop, containerd.io
+// packages, k3s' embedded containerd, ...).
+const defaultContainerdEndpoint = "/run/containerd/containerd.sock"
+
+// containerdCgroupRE matches the cgroup scope containerd's CRI shim
+// creates for a container, e.g.
+// .../kubepods-burstable-pod<uid>.slice/cri-containerd-<id>.scope.
+var containerdCgroupRE = regexp.MustCompile(`cri-containerd-([0-9a-f]{64})\.scope$`)
+
+// NewContainerdResolver returns a ContainerResolver backed by
+// containerd's CRI plugin. endpoint is the containerd CRI unix socket;
+// pass "" to use defaultContainerdEndpoint.
+func NewContainerdResolver(endpoint string) (ContainerResolver, error) {
+	if endpoint == "" {
+		endpoint = defaultContainerdEndpoint
+	}
+	return newCRIResolver(endpoint, containerdCgroupRE)
+}
@@ -0,0 +1,71 @@
+//go:build linux
+
+package conntracer
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1"
+)
+
+// criResolver implements ContainerResolver against any CRI-compatible
+// runtime (containerd, CRI-O) over its unix socket. The cgroup path
+// regexp differs per runtime since each container runtime shim names
+// its cgroup scope differently; everything else is shared.
+type criResolver struct {
+	client      runtimeapi.RuntimeServiceClient
+	cgroupRE    *regexp.Regexp
+	dialTimeout time.Duration
+}
+
+func newCRIResolver(endpoint string, cgroupRE *regexp.Regexp) (*criResolver, error) {
+	conn, err := grpc.NewClient("unix://"+endpoint, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial CRI endpoint %s: %w", endpoint, err)
+	}
+	return &criResolver{
+		client:      runtimeapi.NewRuntimeServiceClient(conn),
+		cgroupRE:    cgroupRE,
+		dialTimeout: 2 * time.Second,
+	}, nil
+}
+
+// Resolve implements ContainerResolver.
+func (r *criResolver) Resolve(cgroupID uint64) (*ContainerInfo, error) {
+	path, err := cgroupPathForID(cgroupID)
+	if err != nil {
+		return nil, err
+	}
+	if path == "" {
+		return nil, nil
+	}
+
+	m := r.cgroupRE.FindStringSubmatch(path)
+	if m == nil {
+		return nil, nil
+	}
+	containerID := m[1]
+
+	ctx, cancel := context.WithTimeout(context.Background(), r.dialTimeout)
+	defer cancel()
+
+	status, err := r.client.ContainerStatus(ctx, &runtimeapi.ContainerStatusRequest{ContainerId: containerID})
+	if err != nil {
+		// The container may have already exited between the kprobe
+		// firing and this lookup; treat it as unresolved rather than
+		// a hard error.
+		return &ContainerInfo{ContainerID: containerID}, nil
+	}
+
+	labels := status.GetStatus().GetLabels()
+	return &ContainerInfo{
+		ContainerID:  containerID,
+		PodName:      labels["io.kubernetes.pod.name"],
+		PodNamespace: labels["io.kubernetes.pod.namespace"],
+	}, nil
+}
@@ -0,0 +1,22 @@
+//go:build linux
+
+package conntracer
+
+import "regexp"
+
+// defaultCRIOEndpoint is where CRI-O listens for CRI requests by
+// default.
+const defaultCRIOEndpoint = "/run/crio/crio.sock"
+
+// crioCgroupRE matches the cgroup scope crio creates for a container,
+// e.g. .../kubepods-burstable-pod<uid>.slice/crio-<id>.scope.
+var crioCgroupRE = regexp.MustCompile(`crio-([0-9a-f]{64})\.scope$`)
+
+// NewCRIOResolver returns a ContainerResolver backed by CRI-O. endpoint
+// is the CRI-O CRI unix socket; pass "" to use defaultCRIOEndpoint.
+func NewCRIOResolver(endpoint string) (ContainerResolver, error) {
+	if endpoint == "" {
+		endpoint = defaultCRIOEndpoint
+	}
+	return newCRIResolver(endpoint, crioCgroupRE)
+}
@@ -0,0 +1,65 @@
+//go:build linux
+
+package conntracer
+
+import (
+	"context"
+	"regexp"
+	"time"
+
+	"github.com/docker/docker/client"
+)
+
+// dockerCgroupRE matches both the cgroupfs and systemd cgroup drivers:
+// /docker/<id> and docker-<id>.scope.
+var dockerCgroupRE = regexp.MustCompile(`docker[-/]([0-9a-f]{64})(?:\.scope)?$`)
+
+// dockerResolver implements ContainerResolver against a plain Docker
+// daemon. Unlike the CRI-based resolvers, it has no notion of pods, so
+// PodName/PodNamespace are only populated when the container carries
+// the Kubernetes dockershim labels (pre-1.24 clusters).
+type dockerResolver struct {
+	cli *client.Client
+}
+
+// NewDockerResolver returns a ContainerResolver backed by the Docker
+// daemon reachable via the standard DOCKER_HOST/DOCKER_* environment
+// variables.
+func NewDockerResolver() (ContainerResolver, error) {
+	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	if err != nil {
+		return nil, err
+	}
+	return &dockerResolver{cli: cli}, nil
+}
+
+// Resolve implements ContainerResolver.
+func (r *dockerResolver) Resolve(cgroupID uint64) (*ContainerInfo, error) {
+	path, err := cgroupPathForID(cgroupID)
+	if err != nil {
+		return nil, err
+	}
+	if path == "" {
+		return nil, nil
+	}
+
+	m := dockerCgroupRE.FindStringSubmatch(path)
+	if m == nil {
+		return nil, nil
+	}
+	containerID := m[1]
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	c, err := r.cli.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return &ContainerInfo{ContainerID: containerID}, nil
+	}
+
+	return &ContainerInfo{
+		ContainerID:  containerID,
+		PodName:      c.Config.Labels["io.kubernetes.pod.name"],
+		PodNamespace: c.Config.Labels["io.kubernetes.pod.namespace"],
+	}, nil
+}
@@ -0,0 +1,43 @@
+//go:build linux
+
+package conntracer
+
+import "context"
+
+// Exporter is a sink for aggregated Flow batches, called once per
+// Tracer polling tick alongside the user's callback passed to
+// Tracer.Start. Built-in implementations are NewPrometheusExporter,
+// NewOTelExporter and NewJSONLinesExporter; callers can also implement
+// it directly to plug conntracer into any other observability stack.
+type Exporter interface {
+	Export(ctx context.Context, flows []*Flow) error
+	Close() error
+}
+
+// StreamingExporter is the TracerStreaming equivalent of Exporter: it
+// is called once per flow as TracerStreaming.Start decodes it off the
+// ring buffer or perf event array, in addition to the flow being sent
+// on the channel passed to Start.
+type StreamingExporter interface {
+	ExportFlow(ctx context.Context, flow *Flow) error
+	Close() error
+}
+
+// exportFlows calls Export on every configured Exporter, collecting
+// and returning the first error while still giving every exporter a
+// chance to run.
+func exportFlows(ctx context.Context, exporters []Exporter, flows []*Flow) error {
+	var firstErr error
+	for _, e := range exporters {
+		if err := e.Export(ctx, flows); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func closeExporters(exporters []Exporter) {
+	for _, e := range exporters {
+		e.Close()
+	}
+}
@@ -0,0 +1,98 @@
+//go:build linux
+
+package conntracer
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+)
+
+// jsonLinesExporter writes one JSON object per Flow per line. It's the
+// simplest Exporter and mainly useful for ad-hoc debugging or piping
+// into another log-based pipeline (jq, Loki, ...).
+type jsonLinesExporter struct {
+	enc *json.Encoder
+	w   io.Writer
+}
+
+// jsonFlow is the wire format written by jsonLinesExporter; it mirrors
+// Flow but with string-rendered addresses and direction so the output
+// doesn't require knowing the BPF-side enums to read.
+type jsonFlow struct {
+	SAddr        string `json:"saddr"`
+	DAddr        string `json:"daddr"`
+	ProcessName  string `json:"process_name"`
+	LPort        uint16 `json:"lport"`
+	Direction    string `json:"direction"`
+	LastPID      uint32 `json:"last_pid"`
+	L4Proto      uint8  `json:"l4_proto"`
+	CgroupID     uint64 `json:"cgroup_id,omitempty"`
+	ContainerID  string `json:"container_id,omitempty"`
+	PodName      string `json:"pod_name,omitempty"`
+	PodNamespace string `json:"pod_namespace,omitempty"`
+	SentBytes    uint64 `json:"sent_bytes,omitempty"`
+	RecvBytes    uint64 `json:"recv_bytes,omitempty"`
+}
+
+// NewJSONLinesExporter returns an Exporter that writes each Flow as a
+// JSON object to w, one per line. Pass os.Stdout for the stdout sink.
+func NewJSONLinesExporter(w io.Writer) Exporter {
+	return &jsonLinesExporter{enc: json.NewEncoder(w), w: w}
+}
+
+// Export implements Exporter.
+func (e *jsonLinesExporter) Export(_ context.Context, flows []*Flow) error {
+	for _, f := range flows {
+		if err := e.enc.Encode(flowToJSON(f)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close implements Exporter. It closes w when w is an io.Closer other
+// than os.Stdout, which a library must not close; otherwise it is a
+// no-op.
+func (e *jsonLinesExporter) Close() error {
+	if c, ok := e.w.(io.Closer); ok && e.w != os.Stdout {
+		return c.Close()
+	}
+	return nil
+}
+
+func flowToJSON(f *Flow) jsonFlow {
+	j := jsonFlow{
+		ProcessName:  f.ProcessName,
+		LPort:        f.LPort,
+		Direction:    directionString(f.Direction),
+		LastPID:      f.LastPID,
+		L4Proto:      f.L4Proto,
+		CgroupID:     f.CgroupID,
+		ContainerID:  f.ContainerID,
+		PodName:      f.PodName,
+		PodNamespace: f.PodNamespace,
+	}
+	if f.SAddr != nil {
+		j.SAddr = f.SAddr.String()
+	}
+	if f.DAddr != nil {
+		j.DAddr = f.DAddr.String()
+	}
+	if f.Stat != nil {
+		j.SentBytes = f.Stat.sentBytes
+		j.RecvBytes = f.Stat.recvBytes
+	}
+	return j
+}
+
+func directionString(d FlowDirection) string {
+	switch d {
+	case FlowActive:
+		return "active"
+	case FlowPassive:
+		return "passive"
+	}
+	return "unknown"
+}
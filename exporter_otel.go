@@ -0,0 +1,82 @@
+//go:build linux
+
+package conntracer
+
+import (
+	"context"
+	"strconv"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// otelExporter emits the same per-flow counters as promExporter, but
+// as OTLP metrics through the go.opentelemetry.io/otel/metric API
+// instead of a pull-based /metrics endpoint.
+type otelExporter struct {
+	newConnections metric.Int64Counter
+	sentBytes      metric.Int64Counter
+	recvBytes      metric.Int64Counter
+}
+
+// NewOTelExporter returns an Exporter that records flow counters on
+// meter. Callers own the MeterProvider/exporter pipeline (OTLP gRPC,
+// stdout, ...) that meter is backed by.
+func NewOTelExporter(meter metric.Meter) (Exporter, error) {
+	newConnections, err := meter.Int64Counter("conntracer.new_connections",
+		metric.WithDescription("Number of new connections observed per flow."))
+	if err != nil {
+		return nil, err
+	}
+	sentBytes, err := meter.Int64Counter("conntracer.sent_bytes",
+		metric.WithDescription("Bytes sent per flow."))
+	if err != nil {
+		return nil, err
+	}
+	recvBytes, err := meter.Int64Counter("conntracer.recv_bytes",
+		metric.WithDescription("Bytes received per flow."))
+	if err != nil {
+		return nil, err
+	}
+	return &otelExporter{
+		newConnections: newConnections,
+		sentBytes:      sentBytes,
+		recvBytes:      recvBytes,
+	}, nil
+}
+
+// Export implements Exporter.
+func (e *otelExporter) Export(ctx context.Context, flows []*Flow) error {
+	for _, f := range flows {
+		attrs := metric.WithAttributes(otelFlowAttrs(f)...)
+		e.newConnections.Add(ctx, 1, attrs)
+		if f.Stat != nil {
+			e.sentBytes.Add(ctx, int64(f.Stat.sentBytes), attrs)
+			e.recvBytes.Add(ctx, int64(f.Stat.recvBytes), attrs)
+		}
+	}
+	return nil
+}
+
+// Close implements Exporter. Flushing/shutting down the underlying
+// MeterProvider is the caller's responsibility since it owns it.
+func (e *otelExporter) Close() error {
+	return nil
+}
+
+func otelFlowAttrs(f *Flow) []attribute.KeyValue {
+	var saddr, daddr string
+	if f.SAddr != nil {
+		saddr = f.SAddr.String()
+	}
+	if f.DAddr != nil {
+		daddr = f.DAddr.String()
+	}
+	return []attribute.KeyValue{
+		attribute.String("saddr", saddr),
+		attribute.String("daddr", daddr),
+		attribute.String("lport", strconv.Itoa(int(f.LPort))),
+		attribute.String("direction", directionString(f.Direction)),
+		attribute.String("l4_proto", strconv.Itoa(int(f.L4Proto))),
+	}
+}
@@ -0,0 +1,96 @@
+//go:build linux
+
+package conntracer
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// promExporter is an Exporter that keeps per-(saddr,daddr,lport,
+// direction,l4_proto) counters and exposes them as a
+// prometheus.Collector. It does not serve /metrics itself; callers
+// register it on their own registry (prometheus.MustRegister or a
+// dedicated *prometheus.Registry) and wire up the HTTP handler.
+type promExporter struct {
+	newConnections *prometheus.CounterVec
+	sentBytes      *prometheus.CounterVec
+	recvBytes      *prometheus.CounterVec
+}
+
+var promLabels = []string{"saddr", "daddr", "lport", "direction", "l4_proto"}
+
+// NewPrometheusExporter returns an Exporter that is itself a
+// prometheus.Collector; register it with a prometheus.Registerer to
+// expose conntracer_new_connections_total, conntracer_sent_bytes_total
+// and conntracer_recv_bytes_total.
+func NewPrometheusExporter() (Exporter, prometheus.Collector) {
+	e := &promExporter{
+		newConnections: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "conntracer_new_connections_total",
+			Help: "Number of new connections observed per flow.",
+		}, promLabels),
+		sentBytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "conntracer_sent_bytes_total",
+			Help: "Bytes sent per flow.",
+		}, promLabels),
+		recvBytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "conntracer_recv_bytes_total",
+			Help: "Bytes received per flow.",
+		}, promLabels),
+	}
+	return e, e
+}
+
+// Describe implements prometheus.Collector.
+func (e *promExporter) Describe(ch chan<- *prometheus.Desc) {
+	e.newConnections.Describe(ch)
+	e.sentBytes.Describe(ch)
+	e.recvBytes.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (e *promExporter) Collect(ch chan<- prometheus.Metric) {
+	e.newConnections.Collect(ch)
+	e.sentBytes.Collect(ch)
+	e.recvBytes.Collect(ch)
+}
+
+// Export implements Exporter.
+func (e *promExporter) Export(_ context.Context, flows []*Flow) error {
+	for _, f := range flows {
+		labels := promFlowLabels(f)
+		e.newConnections.With(labels).Inc()
+		if f.Stat != nil {
+			e.sentBytes.With(labels).Add(float64(f.Stat.sentBytes))
+			e.recvBytes.With(labels).Add(float64(f.Stat.recvBytes))
+		}
+	}
+	return nil
+}
+
+// Close implements Exporter. The counter vectors have no resources to
+// release; unregistering them, if desired, is the caller's
+// responsibility since it owns the registry.
+func (e *promExporter) Close() error {
+	return nil
+}
+
+func promFlowLabels(f *Flow) prometheus.Labels {
+	var saddr, daddr string
+	if f.SAddr != nil {
+		saddr = f.SAddr.String()
+	}
+	if f.DAddr != nil {
+		daddr = f.DAddr.String()
+	}
+	return prometheus.Labels{
+		"saddr":     saddr,
+		"daddr":     daddr,
+		"lport":     strconv.Itoa(int(f.LPort)),
+		"direction": directionString(f.Direction),
+		"l4_proto":  strconv.Itoa(int(f.L4Proto)),
+	}
+}
@@ -0,0 +1,214 @@
+//go:build linux
+
+package conntracer
+
+import (
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/cilium/ebpf"
+)
+
+// Filter restricts which flows the kernel programs admit into the
+// flows map, so unwanted traffic never reaches userspace. A dimension
+// left empty (e.g. no AllowPorts) is not filtered at all; DenyCIDRs is
+// always enforced and takes precedence over AllowCIDRs.
+type Filter struct {
+	AllowCIDRs    []*net.IPNet
+	DenyCIDRs     []*net.IPNet
+	AllowPorts    []uint16
+	AllowL4Protos []uint8
+	AllowPIDs     []uint32
+}
+
+// filterAllowMarker is the value stored against every admitted key in
+// the filter allow/deny maps; only its presence is consulted in BPF.
+const filterAllowMarker uint8 = 1
+
+// filterConfigValue mirrors bpf/conntracer.h's filter_config and is
+// shared by both Tracer and TracerStreaming, whose generated
+// conntracerFilterConfig/conntracerstreamingFilterConfig types have the
+// identical layout.
+type filterConfigValue struct {
+	AllowCidrEnabled     uint8
+	DenyCidrEnabled      uint8
+	AllowPortsEnabled    uint8
+	AllowL4protosEnabled uint8
+	AllowPidsEnabled     uint8
+}
+
+// lpmKeyV4/lpmKeyV6 mirror bpf/conntracer.h's lpm_key_v4/lpm_key_v6.
+type lpmKeyV4 struct {
+	Prefixlen uint32
+	Addr      [4]byte
+}
+
+type lpmKeyV6 struct {
+	Prefixlen uint32
+	Addr      [16]byte
+}
+
+// filterMaps is the set of BPF maps a Filter is applied to; Tracer and
+// TracerStreaming each expose one.
+type filterMaps struct {
+	config        *ebpf.Map
+	allowCidr4    *ebpf.Map
+	allowCidr6    *ebpf.Map
+	denyCidr4     *ebpf.Map
+	denyCidr6     *ebpf.Map
+	allowPorts    *ebpf.Map
+	allowL4Protos *ebpf.Map
+	allowPIDs     *ebpf.Map
+}
+
+// SetFilter installs filter, discarding anything set by a previous
+// SetFilter/UpdateFilter call.
+func (t *Tracer) SetFilter(filter Filter) error {
+	return t.filterMaps().apply(filter, true)
+}
+
+// UpdateFilter merges filter into the currently loaded one without
+// clearing existing entries first, so e.g. a newly discovered pod CIDR
+// can be added without momentarily disabling filtering for everything
+// else.
+func (t *Tracer) UpdateFilter(filter Filter) error {
+	return t.filterMaps().apply(filter, false)
+}
+
+func (t *Tracer) filterMaps() *filterMaps {
+	return &filterMaps{
+		config:        t.objs.FilterConfig,
+		allowCidr4:    t.objs.FilterAllowCidr4,
+		allowCidr6:    t.objs.FilterAllowCidr6,
+		denyCidr4:     t.objs.FilterDenyCidr4,
+		denyCidr6:     t.objs.FilterDenyCidr6,
+		allowPorts:    t.objs.FilterAllowPorts,
+		allowL4Protos: t.objs.FilterAllowL4protos,
+		allowPIDs:     t.objs.FilterAllowPids,
+	}
+}
+
+// SetFilter installs filter, discarding anything set by a previous
+// SetFilter/UpdateFilter call.
+func (t *TracerStreaming) SetFilter(filter Filter) error {
+	return t.filterMaps().apply(filter, true)
+}
+
+// UpdateFilter merges filter into the currently loaded one without
+// clearing existing entries first.
+func (t *TracerStreaming) UpdateFilter(filter Filter) error {
+	return t.filterMaps().apply(filter, false)
+}
+
+func (t *TracerStreaming) filterMaps() *filterMaps {
+	return t.filters
+}
+
+func (m *filterMaps) apply(filter Filter, clear bool) error {
+	if clear {
+		for _, mp := range []*ebpf.Map{m.allowCidr4, m.allowCidr6, m.denyCidr4, m.denyCidr6, m.allowPorts, m.allowL4Protos, m.allowPIDs} {
+			if err := clearMap(mp); err != nil {
+				return fmt.Errorf("failed to clear filter map: %w", err)
+			}
+		}
+	}
+
+	for _, n := range filter.AllowCIDRs {
+		if err := putCIDR(m.allowCidr4, m.allowCidr6, n); err != nil {
+			return fmt.Errorf("failed to update filter_allow_cidr: %w", err)
+		}
+	}
+	for _, n := range filter.DenyCIDRs {
+		if err := putCIDR(m.denyCidr4, m.denyCidr6, n); err != nil {
+			return fmt.Errorf("failed to update filter_deny_cidr: %w", err)
+		}
+	}
+	for _, p := range filter.AllowPorts {
+		if err := m.allowPorts.Put(p, filterAllowMarker); err != nil {
+			return fmt.Errorf("failed to update filter_allow_ports: %w", err)
+		}
+	}
+	for _, p := range filter.AllowL4Protos {
+		if err := m.allowL4Protos.Put(p, filterAllowMarker); err != nil {
+			return fmt.Errorf("failed to update filter_allow_l4protos: %w", err)
+		}
+	}
+	for _, pid := range filter.AllowPIDs {
+		if err := m.allowPIDs.Put(pid, filterAllowMarker); err != nil {
+			return fmt.Errorf("failed to update filter_allow_pids: %w", err)
+		}
+	}
+
+	cfg := filterConfigValue{
+		AllowCidrEnabled:     boolToU8(len(filter.AllowCIDRs) > 0),
+		DenyCidrEnabled:      boolToU8(len(filter.DenyCIDRs) > 0),
+		AllowPortsEnabled:    boolToU8(len(filter.AllowPorts) > 0),
+		AllowL4protosEnabled: boolToU8(len(filter.AllowL4Protos) > 0),
+		AllowPidsEnabled:     boolToU8(len(filter.AllowPIDs) > 0),
+	}
+	var zero uint32
+	if !clear {
+		// Merge with whatever is already installed so a dimension this
+		// call didn't mention (and so left untouched in the map) stays
+		// enabled, instead of UpdateFilter silently turning it off.
+		var existing filterConfigValue
+		if err := m.config.Lookup(zero, &existing); err == nil {
+			cfg.AllowCidrEnabled |= existing.AllowCidrEnabled
+			cfg.DenyCidrEnabled |= existing.DenyCidrEnabled
+			cfg.AllowPortsEnabled |= existing.AllowPortsEnabled
+			cfg.AllowL4protosEnabled |= existing.AllowL4protosEnabled
+			cfg.AllowPidsEnabled |= existing.AllowPidsEnabled
+		} else if !errors.Is(err, ebpf.ErrKeyNotExist) {
+			return fmt.Errorf("failed to read filter_config: %w", err)
+		}
+	}
+	if err := m.config.Put(zero, cfg); err != nil {
+		return fmt.Errorf("failed to update filter_config: %w", err)
+	}
+	return nil
+}
+
+// putCIDR stores n as allowed/denied in whichever of m4/m6 matches its
+// address family, keyed by prefix length so the BPF side can do a
+// longest-prefix-match lookup.
+func putCIDR(m4, m6 *ebpf.Map, n *net.IPNet) error {
+	ones, bits := n.Mask.Size()
+	if bits == net.IPv4len*8 {
+		key := lpmKeyV4{Prefixlen: uint32(ones)}
+		copy(key.Addr[:], n.IP.To4())
+		return m4.Put(key, filterAllowMarker)
+	}
+	key := lpmKeyV6{Prefixlen: uint32(ones)}
+	copy(key.Addr[:], n.IP.To16())
+	return m6.Put(key, filterAllowMarker)
+}
+
+// clearMap deletes every key currently in m, used to reset a filter map
+// before SetFilter repopulates it.
+func clearMap(m *ebpf.Map) error {
+	var key, value []byte
+	keys := make([][]byte, 0)
+
+	it := m.Iterate()
+	for it.Next(&key, &value) {
+		keys = append(keys, append([]byte(nil), key...))
+	}
+	if err := it.Err(); err != nil {
+		return err
+	}
+
+	for _, k := range keys {
+		if err := m.Delete(k); err != nil && !errors.Is(err, ebpf.ErrKeyNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+func boolToU8(b bool) uint8 {
+	if b {
+		return 1
+	}
+	return 0
+}
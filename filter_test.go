@@ -0,0 +1,223 @@
+//go:build linux
+
+package conntracer
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"unsafe"
+
+	"github.com/cilium/ebpf"
+)
+
+// bpfFNoPrealloc mirrors BPF_F_NO_PREALLOC from <linux/bpf.h>, used by
+// the LPM trie maps under test the same way bpf/conntracer.c does.
+const bpfFNoPrealloc = 1
+
+func newTestFilterMaps(t *testing.T) *filterMaps {
+	t.Helper()
+
+	newMap := func(spec *ebpf.MapSpec) *ebpf.Map {
+		m, err := ebpf.NewMap(spec)
+		if err != nil {
+			t.Fatalf("failed to create %s map: %v", spec.Type, err)
+		}
+		t.Cleanup(func() { m.Close() })
+		return m
+	}
+
+	return &filterMaps{
+		config: newMap(&ebpf.MapSpec{
+			Type:       ebpf.Array,
+			KeySize:    4,
+			ValueSize:  uint32(unsafe.Sizeof(filterConfigValue{})),
+			MaxEntries: 1,
+		}),
+		allowCidr4: newMap(&ebpf.MapSpec{
+			Type:       ebpf.LPMTrie,
+			Flags:      bpfFNoPrealloc,
+			KeySize:    uint32(unsafe.Sizeof(lpmKeyV4{})),
+			ValueSize:  1,
+			MaxEntries: 1024,
+		}),
+		allowCidr6: newMap(&ebpf.MapSpec{
+			Type:       ebpf.LPMTrie,
+			Flags:      bpfFNoPrealloc,
+			KeySize:    uint32(unsafe.Sizeof(lpmKeyV6{})),
+			ValueSize:  1,
+			MaxEntries: 1024,
+		}),
+		denyCidr4: newMap(&ebpf.MapSpec{
+			Type:       ebpf.LPMTrie,
+			Flags:      bpfFNoPrealloc,
+			KeySize:    uint32(unsafe.Sizeof(lpmKeyV4{})),
+			ValueSize:  1,
+			MaxEntries: 1024,
+		}),
+		denyCidr6: newMap(&ebpf.MapSpec{
+			Type:       ebpf.LPMTrie,
+			Flags:      bpfFNoPrealloc,
+			KeySize:    uint32(unsafe.Sizeof(lpmKeyV6{})),
+			ValueSize:  1,
+			MaxEntries: 1024,
+		}),
+		allowPorts: newMap(&ebpf.MapSpec{
+			Type:       ebpf.Hash,
+			KeySize:    2,
+			ValueSize:  1,
+			MaxEntries: 1024,
+		}),
+		allowL4Protos: newMap(&ebpf.MapSpec{
+			Type:       ebpf.Hash,
+			KeySize:    1,
+			ValueSize:  1,
+			MaxEntries: 16,
+		}),
+		allowPIDs: newMap(&ebpf.MapSpec{
+			Type:       ebpf.Hash,
+			KeySize:    4,
+			ValueSize:  1,
+			MaxEntries: 1024,
+		}),
+	}
+}
+
+func readFilterConfig(t *testing.T, m *filterMaps) filterConfigValue {
+	t.Helper()
+	var cfg filterConfigValue
+	var zero uint32
+	if err := m.config.Lookup(zero, &cfg); err != nil {
+		t.Fatalf("failed to read filter_config: %v", err)
+	}
+	return cfg
+}
+
+func mapHasKey(t *testing.T, m *ebpf.Map, key any) bool {
+	t.Helper()
+	var value uint8
+	err := m.Lookup(key, &value)
+	if err == nil {
+		return true
+	}
+	if errors.Is(err, ebpf.ErrKeyNotExist) {
+		return false
+	}
+	t.Fatalf("unexpected lookup error: %v", err)
+	return false
+}
+
+// TestFilterMapsApply_UpdateMergesEnabledFlags guards against the bug
+// fixed in a previous commit, where UpdateFilter recomputed
+// filter_config purely from the Filter passed to that call and
+// clobbered the enabled flag of every dimension it didn't mention.
+func TestFilterMapsApply_UpdateMergesEnabledFlags(t *testing.T) {
+	m := newTestFilterMaps(t)
+
+	if err := m.apply(Filter{AllowPorts: []uint16{80}}, true); err != nil {
+		t.Fatalf("SetFilter: %v", err)
+	}
+	cfg := readFilterConfig(t, m)
+	if cfg.AllowPortsEnabled != 1 {
+		t.Fatalf("AllowPortsEnabled = %d, want 1", cfg.AllowPortsEnabled)
+	}
+	if cfg.AllowCidrEnabled != 0 {
+		t.Fatalf("AllowCidrEnabled = %d, want 0", cfg.AllowCidrEnabled)
+	}
+
+	_, cidr, err := net.ParseCIDR("10.0.0.0/8")
+	if err != nil {
+		t.Fatalf("ParseCIDR: %v", err)
+	}
+	if err := m.apply(Filter{AllowCIDRs: []*net.IPNet{cidr}}, false); err != nil {
+		t.Fatalf("UpdateFilter: %v", err)
+	}
+
+	cfg = readFilterConfig(t, m)
+	if cfg.AllowPortsEnabled != 1 {
+		t.Fatalf("AllowPortsEnabled after UpdateFilter = %d, want 1 (must not be cleared)", cfg.AllowPortsEnabled)
+	}
+	if cfg.AllowCidrEnabled != 1 {
+		t.Fatalf("AllowCidrEnabled after UpdateFilter = %d, want 1", cfg.AllowCidrEnabled)
+	}
+	if !mapHasKey(t, m.allowPorts, uint16(80)) {
+		t.Fatal("filter_allow_ports lost its entry after UpdateFilter")
+	}
+}
+
+// TestFilterMapsApply_SetFilterClearsPreviousEntries checks the other
+// half of the contract: SetFilter (clear=true) must discard whatever a
+// previous SetFilter/UpdateFilter installed.
+func TestFilterMapsApply_SetFilterClearsPreviousEntries(t *testing.T) {
+	m := newTestFilterMaps(t)
+
+	if err := m.apply(Filter{AllowPorts: []uint16{80}}, true); err != nil {
+		t.Fatalf("first SetFilter: %v", err)
+	}
+	_, cidr, err := net.ParseCIDR("10.0.0.0/8")
+	if err != nil {
+		t.Fatalf("ParseCIDR: %v", err)
+	}
+	if err := m.apply(Filter{AllowCIDRs: []*net.IPNet{cidr}}, true); err != nil {
+		t.Fatalf("second SetFilter: %v", err)
+	}
+
+	cfg := readFilterConfig(t, m)
+	if cfg.AllowPortsEnabled != 0 {
+		t.Fatalf("AllowPortsEnabled = %d, want 0 after a fresh SetFilter", cfg.AllowPortsEnabled)
+	}
+	if cfg.AllowCidrEnabled != 1 {
+		t.Fatalf("AllowCidrEnabled = %d, want 1", cfg.AllowCidrEnabled)
+	}
+	if mapHasKey(t, m.allowPorts, uint16(80)) {
+		t.Fatal("filter_allow_ports still has port 80 after a fresh SetFilter")
+	}
+}
+
+func TestPutCIDR(t *testing.T) {
+	m := newTestFilterMaps(t)
+
+	_, v4, err := net.ParseCIDR("192.168.1.0/24")
+	if err != nil {
+		t.Fatalf("ParseCIDR v4: %v", err)
+	}
+	if err := putCIDR(m.allowCidr4, m.allowCidr6, v4); err != nil {
+		t.Fatalf("putCIDR v4: %v", err)
+	}
+	key4 := lpmKeyV4{Prefixlen: 24}
+	copy(key4.Addr[:], v4.IP.To4())
+	if !mapHasKey(t, m.allowCidr4, key4) {
+		t.Fatal("filter_allow_cidr4 missing the inserted /24")
+	}
+
+	_, v6, err := net.ParseCIDR("2001:db8::/32")
+	if err != nil {
+		t.Fatalf("ParseCIDR v6: %v", err)
+	}
+	if err := putCIDR(m.allowCidr4, m.allowCidr6, v6); err != nil {
+		t.Fatalf("putCIDR v6: %v", err)
+	}
+	key6 := lpmKeyV6{Prefixlen: 32}
+	copy(key6.Addr[:], v6.IP.To16())
+	if !mapHasKey(t, m.allowCidr6, key6) {
+		t.Fatal("filter_allow_cidr6 missing the inserted /32")
+	}
+}
+
+func TestClearMap(t *testing.T) {
+	m := newTestFilterMaps(t)
+
+	for _, port := range []uint16{80, 443} {
+		if err := m.allowPorts.Put(port, filterAllowMarker); err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+	}
+
+	if err := clearMap(m.allowPorts); err != nil {
+		t.Fatalf("clearMap: %v", err)
+	}
+
+	if mapHasKey(t, m.allowPorts, uint16(80)) || mapHasKey(t, m.allowPorts, uint16(443)) {
+		t.Fatal("clearMap left entries behind")
+	}
+}
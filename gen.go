@@ -0,0 +1,17 @@
+package conntracer
+
+// Run `go generate ./...` after editing anything under bpf/ to
+// regenerate the *_bpfel.go / *_bpfeb.go bindings and embedded object
+// files below. bpf2go compiles each .c against vmlinux.h for CO-RE and
+// emits a Go type for every struct passed via -type, so the layouts in
+// bpf/conntracer.h and the generated Go structs never drift apart.
+
+//go:generate go run github.com/cilium/ebpf/cmd/bpf2go -cc clang -cflags "-O2 -g -Wall -Werror" -type aggregated_flow -type aggregated_flow_tuple -type aggregated_flow_stat -type filter_config -type lpm_key_v4 -type lpm_key_v6 -target bpfel,bpfeb conntracer bpf/conntracer.c -- -I./bpf
+
+//go:generate go run github.com/cilium/ebpf/cmd/bpf2go -cc clang -cflags "-O2 -g -Wall -Werror" -type flow_tuple -type filter_config -type lpm_key_v4 -type lpm_key_v6 -target bpfel,bpfeb conntracerstreaming bpf/conntracer_streaming.c -- -I./bpf
+
+// conntracerstreamingperf is the same program compiled with
+// USE_PERF_EVENT_ARRAY defined, so its flows map is a
+// BPF_MAP_TYPE_PERF_EVENT_ARRAY instead of a ring buffer; NewTracerStreaming
+// picks whichever one the running kernel supports.
+//go:generate go run github.com/cilium/ebpf/cmd/bpf2go -cc clang -cflags "-O2 -g -Wall -Werror -DUSE_PERF_EVENT_ARRAY" -type flow_tuple -type filter_config -type lpm_key_v4 -type lpm_key_v6 -target bpfel,bpfeb conntracerstreamingperf bpf/conntracer_streaming.c -- -I./bpf
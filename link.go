@@ -0,0 +1,104 @@
+//go:build linux
+
+package conntracer
+
+import (
+	"fmt"
+
+	"github.com/cilium/ebpf"
+	cebpflink "github.com/cilium/ebpf/link"
+)
+
+// link is the subset of cilium/ebpf/link.Link that Tracer and
+// TracerStreaming need to keep around so probes can be detached on Close.
+type link interface {
+	Close() error
+}
+
+// attachConntracerPrograms attaches every kprobe/kretprobe in
+// bpf/conntracer.c and returns the resulting links so the caller can
+// detach them on Close. If any attach fails, the already-attached links
+// are closed before returning the error. The IPv6 probes are skipped
+// when disableIPv6 is set, e.g. for kernels without the probed v6
+// functions (tcp_v6_connect, udpv6_sendmsg).
+func attachConntracerPrograms(progs *conntracerPrograms, disableIPv6 bool) ([]link, error) {
+	specs := []kprobeSpec{
+		{symbol: "tcp_v4_connect", prog: progs.KprobeTcpV4Connect},
+		{symbol: "tcp_sendmsg", prog: progs.KprobeTcpSendmsg},
+		{symbol: "tcp_cleanup_rbuf", prog: progs.KprobeTcpCleanupRbuf},
+		{symbol: "udp_sendmsg", prog: progs.KprobeUdpSendmsg},
+		{symbol: "inet_csk_accept", prog: progs.KretprobeInetCskAccept, ret: true},
+	}
+	if !disableIPv6 {
+		specs = append(specs,
+			kprobeSpec{symbol: "tcp_v6_connect", prog: progs.KprobeTcpV6Connect},
+			kprobeSpec{symbol: "udpv6_sendmsg", prog: progs.KprobeUdpv6Sendmsg},
+		)
+	}
+	return attachKprobes(specs)
+}
+
+// attachConntracerStreamingPrograms attaches every kprobe/kretprobe in
+// bpf/conntracer_streaming.c.
+func attachConntracerStreamingPrograms(progs *conntracerstreamingPrograms, disableIPv6 bool) ([]link, error) {
+	specs := []kprobeSpec{
+		{symbol: "tcp_v4_connect", prog: progs.KprobeTcpV4Connect},
+		{symbol: "udp_sendmsg", prog: progs.KprobeUdpSendmsg},
+		{symbol: "inet_csk_accept", prog: progs.KretprobeInetCskAccept, ret: true},
+	}
+	if !disableIPv6 {
+		specs = append(specs,
+			kprobeSpec{symbol: "tcp_v6_connect", prog: progs.KprobeTcpV6Connect},
+			kprobeSpec{symbol: "udpv6_sendmsg", prog: progs.KprobeUdpv6Sendmsg},
+		)
+	}
+	return attachKprobes(specs)
+}
+
+// attachConntracerStreamingPerfPrograms attaches the perf-event-array
+// variant of bpf/conntracer_streaming.c (see conntracerstreamingperf in
+// gen.go); the attached symbols are identical to
+// attachConntracerStreamingPrograms.
+func attachConntracerStreamingPerfPrograms(progs *conntracerstreamingperfPrograms, disableIPv6 bool) ([]link, error) {
+	specs := []kprobeSpec{
+		{symbol: "tcp_v4_connect", prog: progs.KprobeTcpV4Connect},
+		{symbol: "udp_sendmsg", prog: progs.KprobeUdpSendmsg},
+		{symbol: "inet_csk_accept", prog: progs.KretprobeInetCskAccept, ret: true},
+	}
+	if !disableIPv6 {
+		specs = append(specs,
+			kprobeSpec{symbol: "tcp_v6_connect", prog: progs.KprobeTcpV6Connect},
+			kprobeSpec{symbol: "udpv6_sendmsg", prog: progs.KprobeUdpv6Sendmsg},
+		)
+	}
+	return attachKprobes(specs)
+}
+
+type kprobeSpec struct {
+	symbol string
+	prog   *ebpf.Program
+	ret    bool
+}
+
+func attachKprobes(specs []kprobeSpec) ([]link, error) {
+	links := make([]link, 0, len(specs))
+	for _, s := range specs {
+		var (
+			l   cebpflink.Link
+			err error
+		)
+		if s.ret {
+			l, err = cebpflink.Kretprobe(s.symbol, s.prog, nil)
+		} else {
+			l, err = cebpflink.Kprobe(s.symbol, s.prog, nil)
+		}
+		if err != nil {
+			for _, attached := range links {
+				attached.Close()
+			}
+			return nil, fmt.Errorf("failed to attach %s: %w", s.symbol, err)
+		}
+		links = append(links, l)
+	}
+	return links, nil
+}
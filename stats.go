@@ -0,0 +1,56 @@
+//go:build linux
+
+package conntracer
+
+import (
+	"time"
+
+	"github.com/cilium/ebpf"
+)
+
+// BpfProgramStats is a snapshot of the kernel-maintained run counters for
+// a single BPF program. It is only populated when TracerParam.Stats is
+// set, since collecting it has a small but non-zero overhead.
+type BpfProgramStats struct {
+	RunCount   uint64
+	RunTime    time.Duration
+	ProgramTag string
+}
+
+// GetStats fetches stats of BPF program.
+func (t *Tracer) GetStats() (map[int]*BpfProgramStats, error) {
+	progs := &t.objs.conntracerPrograms
+	return programStats(map[string]*ebpf.Program{
+		"kprobe_tcp_v4_connect":     progs.KprobeTcpV4Connect,
+		"kprobe_tcp_v6_connect":     progs.KprobeTcpV6Connect,
+		"kprobe_tcp_sendmsg":        progs.KprobeTcpSendmsg,
+		"kprobe_tcp_cleanup_rbuf":   progs.KprobeTcpCleanupRbuf,
+		"kprobe_udp_sendmsg":        progs.KprobeUdpSendmsg,
+		"kprobe_udpv6_sendmsg":      progs.KprobeUdpv6Sendmsg,
+		"kretprobe_inet_csk_accept": progs.KretprobeInetCskAccept,
+	})
+}
+
+// GetStats fetches stats of BPF program.
+func (t *TracerStreaming) GetStats() (map[int]*BpfProgramStats, error) {
+	return programStats(t.progs)
+}
+
+func programStats(named map[string]*ebpf.Program) (map[int]*BpfProgramStats, error) {
+	stats := make(map[int]*BpfProgramStats, len(named))
+	for _, prog := range named {
+		info, err := prog.Info()
+		if err != nil {
+			return nil, err
+		}
+		id, _ := info.ID()
+		runCount, _ := info.RunCount()
+		runTime, _ := info.Runtime()
+		stats[int(id)] = &BpfProgramStats{
+			RunCount:   runCount,
+			RunTime:    runTime,
+			ProgramTag: info.Tag,
+		}
+	}
+	return stats, nil
+}
@@ -1,38 +1,21 @@
-// +build linux
+//go:build linux
 
 package conntracer
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net"
-	"syscall"
 	"time"
-	"unsafe"
 
-	// Put the C header files into Go module management
-	_ "github.com/yuuki/go-conntracer-bpf/include"
-	_ "github.com/yuuki/go-conntracer-bpf/include/bpf"
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/rlimit"
 	"golang.org/x/sync/errgroup"
 )
 
-/*
-#cgo CFLAGS: -I${SRCDIR}/include
-#cgo LDFLAGS: -lelf -lz
-
-#include <sys/resource.h>
-#include <arpa/inet.h>
-#include <errno.h>
-
-#include <bpf/libbpf.h>
-#include <bpf/bpf.h>
-#include "conntracer.skel.h"
-#include "conntracer.h"
-
-*/
-import "C"
-
 // FlowDirection are bitmask that represents both Active or Passive.
 type FlowDirection uint8
 
@@ -43,32 +26,25 @@ const (
 	FlowActive
 	// FlowPassive are 'passive open'
 	FlowPassive
-
-	// defaultFlowMapOpsBatchSize is batch size of BPF map(flows) lookup_and_delete.
-	defaultFlowMapOpsBatchSize = 10
 )
 
-func flowDirectionFrom(x C.flow_direction) FlowDirection {
+// defaultFlowMapOpsBatchSize is batch size of BPF map(flows) lookup_and_delete.
+const defaultFlowMapOpsBatchSize = 10
+
+func flowDirectionFrom(x uint8) FlowDirection {
 	switch x {
-	case C.FLOW_UNKNOWN:
+	case uint8(FlowUnknown):
 		return FlowUnknown
-	case C.FLOW_ACTIVE:
+	case uint8(FlowActive):
 		return FlowActive
-	case C.FLOW_PASSIVE:
+	case uint8(FlowPassive):
 		return FlowPassive
 	}
 	return FlowUnknown
 }
 
-/*
-aggregated_flow_tuple
-__u32 saddr;
-__u32 daddr;
-__u16 lport;
-__u8 direction;
-__u8 l4_proto;
-*/
-type AggrFlowTuple C.struct_aggregated_flow_tuple
+// AggrFlowTuple is the key of the flows map.
+type AggrFlowTuple conntracerAggregatedFlowTuple
 
 // Flow is a bunch of aggregated flows grouped by listening port.
 type Flow struct {
@@ -80,6 +56,16 @@ type Flow struct {
 	LastPID     uint32
 	L4Proto     uint8
 	Stat        *AggrFlowStat
+
+	// CgroupID is the cgroup v2 id the kernel recorded for LastPID at
+	// insert time. Tracer always populates it; TracerStreaming leaves
+	// it zero, since flow_tuple carries no cgroup id. ContainerID/
+	// PodName/PodNamespace are only set when
+	// TracerParam.ContainerResolver could resolve it.
+	CgroupID     uint64
+	ContainerID  string
+	PodName      string
+	PodNamespace string
 }
 
 // AggrFlowStat is an statistics for aggregated flows.
@@ -99,16 +85,9 @@ func (s *AggrFlowStat) RecvBytes(d time.Duration) float64 {
 	return float64(s.recvBytes) / 1024 / d.Seconds()
 }
 
-/*
-flow_tuple
-__u32 saddr;
-__u32 daddr;
-__u16 sport;
-__u16 dport;
-__u32 pid;
-__u8 l4_proto;
-*/
-type SingleFlowTuple C.struct_flow_tuple
+// SingleFlowTuple is the non-aggregated flow_tuple record that
+// TracerStreaming reads from its flows ring buffer or perf event array.
+type SingleFlowTuple conntracerstreamingFlowTuple
 
 // SingleFlow is a single flow.
 type SingleFlow struct {
@@ -150,9 +129,12 @@ type FlowStat struct {
 
 // Tracer is an object for state retention.
 type Tracer struct {
-	obj      *C.struct_conntracer_bpf
-	stopChan chan struct{}
-	statsFd  int
+	objs        conntracerObjects
+	links       []link
+	stopChan    chan struct{}
+	resolver    *cachedContainerInfo
+	exporters   []Exporter
+	statsCloser io.Closer
 
 	// option
 	batchSize int
@@ -161,37 +143,78 @@ type Tracer struct {
 // TracerParam is a parameter for NewTracer.
 type TracerParam struct {
 	Stats bool
+	// DisableIPv6 skips attaching the IPv6 kprobes, for kernels that
+	// lack the probed v6 functions or don't route v6 traffic at all.
+	DisableIPv6 bool
+	// ContainerResolver, if set, is used to populate Flow.ContainerID,
+	// Flow.PodName and Flow.PodNamespace from Flow.CgroupID. Results
+	// are cached for ContainerCacheTTL so pollFlows doesn't hit it on
+	// every tick. NewTracerStreaming rejects it: flow_tuple carries no
+	// cgroup id and handleFlowRecord has nothing to resolve.
+	ContainerResolver ContainerResolver
+	// ContainerCacheTTL overrides defaultContainerCacheTTL; ignored
+	// when ContainerResolver is nil.
+	ContainerCacheTTL time.Duration
+	// ExcludeCgroups are cgroupfs paths (e.g. the conntracer agent's
+	// own container) whose traffic should never reach the flows map.
+	// Resolved to cgroup ids and pushed into the cgroup_exclude BPF
+	// map at load time. NewTracerStreaming rejects it: conntracer_streaming.c
+	// has no cgroup_exclude map of its own to populate.
+	ExcludeCgroups []string
+	// Exporters receive every flow batch alongside the callback passed
+	// to Start, and are closed when the Tracer is closed.
+	Exporters []Exporter
+	// StreamingExporters receive every flow decoded by TracerStreaming,
+	// alongside the channel passed to TracerStreaming.Start. Ignored by
+	// NewTracer.
+	StreamingExporters []StreamingExporter
 }
 
 // NewTracer creates a Tracer object.
 func NewTracer(param *TracerParam) (*Tracer, error) {
-	// Bump RLIMIT_MEMLOCK to allow BPF sub-system to do anything
-	if err := bumpMemlockRlimit(); err != nil {
+	// Bump RLIMIT_MEMLOCK to allow BPF sub-system to do anything.
+	if err := rlimit.RemoveMemlock(); err != nil {
 		return nil, err
 	}
 
-	obj := C.conntracer_bpf__open_and_load()
-	if obj == nil {
-		return nil, errors.New("failed to open and load BPF object")
+	var objs conntracerObjects
+	if err := loadConntracerObjects(&objs, nil); err != nil {
+		return nil, fmt.Errorf("failed to load BPF objects: %w", err)
+	}
+
+	links, err := attachConntracerPrograms(&objs.conntracerPrograms, param.DisableIPv6)
+	if err != nil {
+		objs.Close()
+		return nil, fmt.Errorf("failed to attach BPF programs: %w", err)
 	}
 
-	cerr := C.conntracer_bpf__attach(obj)
-	if cerr != 0 {
-		return nil, fmt.Errorf("failed to attach BPF programs: %v", C.strerror(-cerr))
+	if err := excludeCgroups(objs.CgroupExclude, param.ExcludeCgroups); err != nil {
+		for _, l := range links {
+			l.Close()
+		}
+		objs.Close()
+		return nil, fmt.Errorf("failed to populate cgroup_exclude map: %w", err)
 	}
 
 	t := &Tracer{
-		obj:       obj,
+		objs:      objs,
+		links:     links,
 		stopChan:  make(chan struct{}),
+		exporters: param.Exporters,
 		batchSize: defaultFlowMapOpsBatchSize,
 	}
 
+	if param.ContainerResolver != nil {
+		t.resolver = newCachedContainerInfo(param.ContainerResolver, param.ContainerCacheTTL)
+	}
+
 	if param.Stats {
-		fd, err := enableBPFStats()
+		closer, err := ebpf.EnableStats(uint32(ebpf.Tracing))
 		if err != nil {
+			t.Close()
 			return nil, err
 		}
-		t.statsFd = fd
+		t.statsCloser = closer
 	}
 
 	return t, nil
@@ -200,15 +223,19 @@ func NewTracer(param *TracerParam) (*Tracer, error) {
 // Close closes tracer.
 func (t *Tracer) Close() {
 	close(t.stopChan)
-	if t.statsFd != 0 {
-		syscall.Close(t.statsFd)
+	for _, l := range t.links {
+		l.Close()
+	}
+	closeExporters(t.exporters)
+	if t.statsCloser != nil {
+		t.statsCloser.Close()
 	}
-	C.conntracer_bpf__destroy(t.obj)
+	t.objs.Close()
 }
 
 // Start starts polling loop.
 func (t *Tracer) Start(cb func([]*Flow) error, interval time.Duration) error {
-	if err := initializeUDPPortBindingMap(t.udpPortBindingMapFD()); err != nil {
+	if err := initializeUDPPortBindingMap(t.objs.UdpPortBinding); err != nil {
 		return err
 	}
 	go t.pollFlows(cb, interval)
@@ -226,7 +253,7 @@ func (t *Tracer) DumpFlows() ([]*Flow, error) {
 	flowChan := make(chan map[AggrFlowTuple]*Flow, 1)
 	statChan := make(chan map[AggrFlowTuple]*AggrFlowStat, 1)
 	eg.Go(func() error {
-		flow, err := dumpAggrFlows(t.flowsMapFD())
+		flow, err := dumpAggrFlows(t.objs.Flows, t.batchSize)
 		if err != nil {
 			return err
 		}
@@ -235,7 +262,7 @@ func (t *Tracer) DumpFlows() ([]*Flow, error) {
 		return nil
 	})
 	eg.Go(func() error {
-		stats, err := dumpAggrFlowStats(t.flowStatsMapFD())
+		stats, err := dumpAggrFlowStats(t.objs.FlowStats, t.batchSize)
 		if err != nil {
 			return err
 		}
@@ -259,19 +286,10 @@ func (t *Tracer) DumpFlows() ([]*Flow, error) {
 		}
 		merged = append(merged, flow)
 	}
-	return merged, nil
-}
-
-func (t *Tracer) flowsMapFD() C.int {
-	return C.bpf_map__fd(t.obj.maps.flows)
-}
 
-func (t *Tracer) flowStatsMapFD() C.int {
-	return C.bpf_map__fd(t.obj.maps.flow_stats)
-}
+	populateContainerInfo(merged, t.resolver)
 
-func (t *Tracer) udpPortBindingMapFD() C.int {
-	return C.bpf_map__fd(t.obj.maps.udp_port_binding)
+	return merged, nil
 }
 
 func (t *Tracer) pollFlows(cb func([]*Flow) error, interval time.Duration) {
@@ -287,6 +305,9 @@ func (t *Tracer) pollFlows(cb func([]*Flow) error, interval time.Duration) {
 			if err != nil {
 				log.Println(err)
 			}
+			if err := exportFlows(context.Background(), t.exporters, flows); err != nil {
+				log.Println(err)
+			}
 			if err := cb(flows); err != nil {
 				log.Println(err)
 			}
@@ -294,64 +315,97 @@ func (t *Tracer) pollFlows(cb func([]*Flow) error, interval time.Duration) {
 	}
 }
 
-func dumpAggrFlows(fd C.int) (map[AggrFlowTuple]*Flow, error) {
-	keys := make([]C.struct_aggregated_flow_tuple, C.MAX_ENTRIES)
-	values := make([]C.struct_aggregated_flow, C.MAX_ENTRIES)
-
-	nRead, err := dumpBpfMap(fd,
-		unsafe.Pointer(&keys[0]), C.sizeof_struct_aggregated_flow_tuple,
-		unsafe.Pointer(&values[0]), C.sizeof_struct_aggregated_flow,
-		defaultFlowMapOpsBatchSize)
-	if err != nil {
-		return nil, err
-	}
+func dumpAggrFlows(m *ebpf.Map, batchSize int) (map[AggrFlowTuple]*Flow, error) {
+	var cursor ebpf.MapBatchCursor
+	keys := make([]conntracerAggregatedFlowTuple, batchSize)
+	values := make([]conntracerAggregatedFlow, batchSize)
 
-	flows := make(map[AggrFlowTuple]*Flow, nRead)
-	for i := uint32(0); i < nRead; i++ {
-		tuple := (AggrFlowTuple)(keys[i])
-		saddr := inetNtop((uint32)(values[i].saddr))
-		daddr := inetNtop((uint32)(values[i].daddr))
-		flows[tuple] = &Flow{
-			SAddr:       &saddr,
-			DAddr:       &daddr,
-			ProcessName: C.GoString((*C.char)(unsafe.Pointer(&values[i].task))),
-			LPort:       (uint16)(values[i].lport),
-			Direction:   flowDirectionFrom((C.flow_direction)(values[i].direction)),
-			L4Proto:     (uint8)(ntohs((uint16)(values[i].l4_proto))),
-			LastPID:     (uint32)(values[i].pid),
+	flows := make(map[AggrFlowTuple]*Flow)
+	for {
+		n, err := m.BatchLookupAndDelete(&cursor, keys, values, nil)
+		for i := 0; i < n; i++ {
+			key, value := keys[i], values[i]
+			tuple := AggrFlowTuple(key)
+			saddr := inetNtop(value.Family, value.Saddr)
+			daddr := inetNtop(value.Family, value.Daddr)
+			flows[tuple] = &Flow{
+				SAddr:       &saddr,
+				DAddr:       &daddr,
+				ProcessName: int8ToString(value.Task[:]),
+				LPort:       value.Lport,
+				Direction:   flowDirectionFrom(value.Direction),
+				L4Proto:     value.L4Proto,
+				LastPID:     value.Pid,
+				CgroupID:    value.CgroupId,
+			}
+		}
+		if errors.Is(err, ebpf.ErrKeyNotExist) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to batch lookup and delete flows map: %w", err)
 		}
 	}
 
 	return flows, nil
 }
 
-func dumpAggrFlowStats(fd C.int) (map[AggrFlowTuple]*AggrFlowStat, error) {
-	keys := make([]C.struct_aggregated_flow_tuple, C.MAX_SINGLE_FLOW_ENTRIES)
-	values := make([]C.struct_aggregated_flow_stat, C.MAX_SINGLE_FLOW_ENTRIES)
-
-	nRead, err := dumpBpfMap(fd,
-		unsafe.Pointer(&keys[0]), C.sizeof_struct_aggregated_flow_tuple,
-		unsafe.Pointer(&values[0]), C.sizeof_struct_aggregated_flow_stat,
-		defaultFlowMapOpsBatchSize)
-	if err != nil {
-		return nil, err
-	}
+func dumpAggrFlowStats(m *ebpf.Map, batchSize int) (map[AggrFlowTuple]*AggrFlowStat, error) {
+	var cursor ebpf.MapBatchCursor
+	keys := make([]conntracerAggregatedFlowTuple, batchSize)
+	values := make([]conntracerAggregatedFlowStat, batchSize)
 
-	stats := make(map[AggrFlowTuple]*AggrFlowStat, nRead)
-	for i := uint32(0); i < nRead; i++ {
-		tuple := (AggrFlowTuple)(keys[i])
-		stat := values[i]
-		stats[tuple] = &AggrFlowStat{
-			Timestamp: time.Unix((int64)(stat.ts_us)*1000*1000, 0),
-			sentBytes: (uint64)(stat.sent_bytes),
-			recvBytes: (uint64)(stat.recv_bytes),
+	stats := make(map[AggrFlowTuple]*AggrFlowStat)
+	for {
+		n, err := m.BatchLookupAndDelete(&cursor, keys, values, nil)
+		for i := 0; i < n; i++ {
+			key, value := keys[i], values[i]
+			tuple := AggrFlowTuple(key)
+			stats[tuple] = &AggrFlowStat{
+				Timestamp: time.Unix(int64(value.TsUs)/1000/1000, 0),
+				sentBytes: value.SentBytes,
+				recvBytes: value.RecvBytes,
+			}
+		}
+		if errors.Is(err, ebpf.ErrKeyNotExist) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to batch lookup and delete flow_stats map: %w", err)
 		}
 	}
 
 	return stats, nil
 }
 
-// GetStats fetches stats of BPF program.
-func (t *Tracer) GetStats() (map[int]*BpfProgramStats, error) {
-	return getBPFAllStats(t.obj.obj)
+// inetNtop converts a 16-byte address as stored in the BPF maps into a
+// net.IP, interpreting it as IPv4 or IPv6 depending on family (one of
+// CONNTRACER_AF_INET/CONNTRACER_AF_INET6 from bpf/conntracer.h).
+func inetNtop(family uint8, addr [16]uint8) net.IP {
+	if family == afInet6 {
+		ip := make(net.IP, net.IPv6len)
+		copy(ip, addr[:])
+		return ip
+	}
+	ip := make(net.IP, net.IPv4len)
+	copy(ip, addr[:net.IPv4len])
+	return ip
+}
+
+// afInet/afInet6 mirror CONNTRACER_AF_INET/CONNTRACER_AF_INET6 in
+// bpf/conntracer.h.
+const (
+	afInet  = 2
+	afInet6 = 10
+)
+
+func int8ToString(b []int8) string {
+	buf := make([]byte, 0, len(b))
+	for _, c := range b {
+		if c == 0 {
+			break
+		}
+		buf = append(buf, byte(c))
+	}
+	return string(buf)
 }
@@ -1,109 +1,229 @@
-package conntracer
-
-/*
-#cgo CFLAGS: -I${SRCDIR}/include
-#cgo LDFLAGS: -lelf -lz
-
-#include <sys/resource.h>
-#include <arpa/inet.h>
-#include <errno.h>
-
-#include <bpf/libbpf.h>
-#include <bpf/bpf.h>
-#include "conntracer_streaming.skel.h"
-#include "conntracer.h"
-
-extern int handleFlow(void *ctx, void *data, size_t size);
-
-int libbpf_print_fn(enum libbpf_print_level level,
-						const char *format, va_list args)
-{
-	// Ignore debug-level libbpf logs
-	if (level > LIBBPF_INFO) {
-		return 0;
-	}
-	return vfprintf(stderr, format, args);
-}
-
-void set_print_fn() {
-	libbpf_set_print(libbpf_print_fn);
-}
+//go:build linux
 
-struct ring_buffer * new_ring_buf(int map_fd) {
-	struct ring_buffer *rb = NULL;
-	rb = ring_buffer__new(map_fd, handleFlow, NULL, NULL);
-	if (rb < 0) {
-		fprintf(stderr, "failed to cretae ring buffer!\n");
-        return NULL;
-	}
-	return rb;
-}
-*/
-import "C"
+package conntracer
 
 import (
+	"bytes"
+	"context"
+	"encoding/binary"
 	"errors"
 	"fmt"
-	"syscall"
-	"time"
-)
+	"io"
+	"log"
+	"os"
 
-const (
-	// BPFRingbufPollingInterval is an interval of polling events in the ringbuffer.
-	BPFRingbufPollingInterval = 50 * time.Millisecond
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/features"
+	"github.com/cilium/ebpf/perf"
+	"github.com/cilium/ebpf/ringbuf"
+	"github.com/cilium/ebpf/rlimit"
 )
 
+// errReaderClosed is returned by flowReader.Read once Stop has closed
+// the underlying ring buffer or perf event array reader.
+var errReaderClosed = errors.New("conntracer: flow reader closed")
+
+// flowReader abstracts over ringbuf.Reader and perf.Reader so Start
+// doesn't need to know which one backs a given TracerStreaming.
+type flowReader interface {
+	Read() ([]byte, error)
+	Close() error
+}
+
 // TracerStreaming is an object for state retention without aggregation.
 type TracerStreaming struct {
-	obj      *C.struct_conntracer_streaming_bpf
-	rb       *C.struct_ring_buffer
-	stopChan chan struct{}
-	statsFd  int
+	closer         io.Closer
+	links          []link
+	reader         flowReader
+	udpPortBinding *ebpf.Map
+	filters        *filterMaps
+	progs          map[string]*ebpf.Program
+	stopChan       chan struct{}
+	exporters      []StreamingExporter
+	statsCloser    io.Closer
 }
 
-// NewTracerStreaming loads tracer without aggregation
+// NewTracerStreaming loads tracer without aggregation. On kernels older
+// than 5.8, which lack BPF_MAP_TYPE_RINGBUF, it transparently falls
+// back to a BPF_MAP_TYPE_PERF_EVENT_ARRAY and a perf.Reader; Start's
+// surface is the same either way.
 func NewTracerStreaming(param *TracerParam) (*TracerStreaming, error) {
-	C.set_print_fn()
+	// conntracer_streaming.c has no cgroup_exclude map and never calls a
+	// ContainerResolver, so silently accepting these would leave a
+	// caller's self-traffic exclusion or container enrichment quietly
+	// unapplied instead of reporting that it was skipped.
+	if len(param.ExcludeCgroups) > 0 {
+		return nil, errors.New("conntracer: TracerParam.ExcludeCgroups is not supported by NewTracerStreaming")
+	}
+	if param.ContainerResolver != nil {
+		return nil, errors.New("conntracer: TracerParam.ContainerResolver is not supported by NewTracerStreaming")
+	}
 
-	// Bump RLIMIT_MEMLOCK to allow BPF sub-system to do anything
-	if err := bumpMemlockRlimit(); err != nil {
+	// Bump RLIMIT_MEMLOCK to allow BPF sub-system to do anything.
+	if err := rlimit.RemoveMemlock(); err != nil {
 		return nil, err
 	}
 
-	obj := C.conntracer_streaming_bpf__open_and_load()
-	if obj == nil {
-		return nil, errors.New("failed to open and load BPF object")
+	if err := features.HaveMapType(ebpf.RingBuf); err != nil {
+		return newTracerStreamingPerf(param)
 	}
+	return newTracerStreamingRingbuf(param)
+}
+
+func newTracerStreamingRingbuf(param *TracerParam) (*TracerStreaming, error) {
+	var objs conntracerstreamingObjects
+	if err := loadConntracerstreamingObjects(&objs, nil); err != nil {
+		return nil, fmt.Errorf("failed to load BPF objects: %w", err)
+	}
+
+	links, err := attachConntracerStreamingPrograms(&objs.conntracerstreamingPrograms, param.DisableIPv6)
+	if err != nil {
+		objs.Close()
+		return nil, fmt.Errorf("failed to attach BPF programs: %w", err)
+	}
+
+	rd, err := ringbuf.NewReader(objs.Flows)
+	if err != nil {
+		for _, l := range links {
+			l.Close()
+		}
+		objs.Close()
+		return nil, fmt.Errorf("failed to create ringbuf reader: %w", err)
+	}
+
+	return newTracerStreaming(param, &objs, links, &ringbufReader{rd}, objs.UdpPortBinding, &filterMaps{
+		config:        objs.FilterConfig,
+		allowCidr4:    objs.FilterAllowCidr4,
+		allowCidr6:    objs.FilterAllowCidr6,
+		denyCidr4:     objs.FilterDenyCidr4,
+		denyCidr6:     objs.FilterDenyCidr6,
+		allowPorts:    objs.FilterAllowPorts,
+		allowL4Protos: objs.FilterAllowL4protos,
+		allowPIDs:     objs.FilterAllowPids,
+	}, map[string]*ebpf.Program{
+		"kprobe_tcp_v4_connect":     objs.KprobeTcpV4Connect,
+		"kprobe_tcp_v6_connect":     objs.KprobeTcpV6Connect,
+		"kprobe_udp_sendmsg":        objs.KprobeUdpSendmsg,
+		"kprobe_udpv6_sendmsg":      objs.KprobeUdpv6Sendmsg,
+		"kretprobe_inet_csk_accept": objs.KretprobeInetCskAccept,
+	})
+}
 
-	ret, err := C.conntracer_streaming_bpf__attach(obj)
-	if ret != 0 {
-		C.conntracer_streaming_bpf__destroy(obj)
-		return nil, fmt.Errorf("failed to attach BPF programs: %v", err)
+func newTracerStreamingPerf(param *TracerParam) (*TracerStreaming, error) {
+	var objs conntracerstreamingperfObjects
+	if err := loadConntracerstreamingperfObjects(&objs, nil); err != nil {
+		return nil, fmt.Errorf("failed to load BPF objects: %w", err)
 	}
 
-	// Set up BPF ring buffer polling.
-	rb := C.new_ring_buf(C.bpf_map__fd(obj.maps.flows))
-	if rb == nil {
-		return nil, fmt.Errorf("failed to create ring buffer")
+	links, err := attachConntracerStreamingPerfPrograms(&objs.conntracerstreamingperfPrograms, param.DisableIPv6)
+	if err != nil {
+		objs.Close()
+		return nil, fmt.Errorf("failed to attach BPF programs: %w", err)
 	}
 
+	rd, err := perf.NewReader(objs.Flows, defaultPerfBufferSize)
+	if err != nil {
+		for _, l := range links {
+			l.Close()
+		}
+		objs.Close()
+		return nil, fmt.Errorf("failed to create perf event reader: %w", err)
+	}
+
+	return newTracerStreaming(param, &objs, links, &perfReader{rd}, objs.UdpPortBinding, &filterMaps{
+		config:        objs.FilterConfig,
+		allowCidr4:    objs.FilterAllowCidr4,
+		allowCidr6:    objs.FilterAllowCidr6,
+		denyCidr4:     objs.FilterDenyCidr4,
+		denyCidr6:     objs.FilterDenyCidr6,
+		allowPorts:    objs.FilterAllowPorts,
+		allowL4Protos: objs.FilterAllowL4protos,
+		allowPIDs:     objs.FilterAllowPids,
+	}, map[string]*ebpf.Program{
+		"kprobe_tcp_v4_connect":     objs.KprobeTcpV4Connect,
+		"kprobe_tcp_v6_connect":     objs.KprobeTcpV6Connect,
+		"kprobe_udp_sendmsg":        objs.KprobeUdpSendmsg,
+		"kprobe_udpv6_sendmsg":      objs.KprobeUdpv6Sendmsg,
+		"kretprobe_inet_csk_accept": objs.KretprobeInetCskAccept,
+	})
+}
+
+// defaultPerfBufferSize is the per-CPU buffer size passed to
+// perf.NewReader, matching the page-multiple cilium/ebpf itself
+// recommends when no hint from the caller is available.
+var defaultPerfBufferSize = os.Getpagesize() * 64
+
+func newTracerStreaming(param *TracerParam, closer io.Closer, links []link, reader flowReader, udpPortBinding *ebpf.Map, filters *filterMaps, progs map[string]*ebpf.Program) (*TracerStreaming, error) {
 	t := &TracerStreaming{
-		obj:      obj,
-		rb:       rb,
-		stopChan: make(chan struct{}),
+		closer:         closer,
+		links:          links,
+		reader:         reader,
+		udpPortBinding: udpPortBinding,
+		filters:        filters,
+		progs:          progs,
+		stopChan:       make(chan struct{}),
+		exporters:      param.StreamingExporters,
 	}
 
 	if param.Stats {
-		fd, err := enableBPFStats()
+		statsCloser, err := ebpf.EnableStats(uint32(ebpf.Tracing))
 		if err != nil {
+			t.Close()
 			return nil, err
 		}
-		t.statsFd = fd
+		t.statsCloser = statsCloser
 	}
 
 	return t, nil
 }
 
+// ringbufReader adapts ringbuf.Reader to flowReader.
+type ringbufReader struct {
+	r *ringbuf.Reader
+}
+
+func (rr *ringbufReader) Read() ([]byte, error) {
+	record, err := rr.r.Read()
+	if errors.Is(err, ringbuf.ErrClosed) {
+		return nil, errReaderClosed
+	}
+	if err != nil {
+		return nil, err
+	}
+	return record.RawSample, nil
+}
+
+func (rr *ringbufReader) Close() error {
+	return rr.r.Close()
+}
+
+// perfReader adapts perf.Reader to flowReader, skipping samples lost to
+// a full per-CPU buffer rather than surfacing them as flows.
+type perfReader struct {
+	r *perf.Reader
+}
+
+func (pr *perfReader) Read() ([]byte, error) {
+	for {
+		record, err := pr.r.Read()
+		if errors.Is(err, perf.ErrClosed) {
+			return nil, errReaderClosed
+		}
+		if err != nil {
+			return nil, err
+		}
+		if record.LostSamples > 0 {
+			log.Printf("conntracer: lost %d flow events (perf event array full)", record.LostSamples)
+			continue
+		}
+		return record.RawSample, nil
+	}
+}
+
+func (pr *perfReader) Close() error {
+	return pr.r.Close()
+}
+
 // TODO: sync.Pool
 var globalFlowChan chan *Flow
 
@@ -111,51 +231,75 @@ var globalFlowChan chan *Flow
 func (t *TracerStreaming) Start(fc chan *Flow) error {
 	globalFlowChan = fc
 
-	if err := initializeUDPPortBindingMap(t.udpPortBindingMapFD()); err != nil {
+	if err := initializeUDPPortBindingMap(t.udpPortBinding); err != nil {
 		return err
 	}
 
-	tick := time.NewTicker(BPFRingbufPollingInterval)
-	defer tick.Stop()
-
 	for {
-		select {
-		case <-t.stopChan:
+		raw, err := t.reader.Read()
+		if errors.Is(err, errReaderClosed) {
 			return nil
-		case <-tick.C:
-			n := C.ring_buffer__poll(t.rb, 10 /* timeout, ms */)
-			if n < 0 {
-				/* Ctrl-C will cause -EINTR */
-				if syscall.Errno(-n) == syscall.EINTR {
-					break
-				}
-				return fmt.Errorf("error polling ring buffer: %d", n)
-			}
+		}
+		if err != nil {
+			return fmt.Errorf("error reading flow event: %w", err)
+		}
+		if err := t.handleFlowRecord(raw); err != nil {
+			return err
 		}
 	}
-	return nil
 }
 
 // Stop stop loop of polling events.
 func (t *TracerStreaming) Stop() {
-	t.stopChan <- struct{}{}
+	close(t.stopChan)
+	t.reader.Close()
 }
 
 // Close closes tracer.
 func (t *TracerStreaming) Close() {
-	close(t.stopChan)
-	if t.statsFd != 0 {
-		syscall.Close(t.statsFd)
+	for _, l := range t.links {
+		l.Close()
+	}
+	for _, e := range t.exporters {
+		e.Close()
+	}
+	if t.statsCloser != nil {
+		t.statsCloser.Close()
 	}
-	C.ring_buffer__free(t.rb)
-	C.conntracer_streaming_bpf__destroy(t.obj)
+	t.closer.Close()
 }
 
-func (t *TracerStreaming) udpPortBindingMapFD() C.int {
-	return C.bpf_map__fd(t.obj.maps.udp_port_binding)
-}
+// handleFlowRecord decodes a raw flow_tuple sample, whether it came off
+// the ring buffer or a perf event array, publishes it on globalFlowChan
+// and forwards it to every configured StreamingExporter.
+func (t *TracerStreaming) handleFlowRecord(raw []byte) error {
+	var tuple conntracerstreamingFlowTuple
+	// The kernel writes flow_tuple in the host's own byte order, so the
+	// decode must use binary.NativeEndian rather than a hardcoded
+	// LittleEndian, which would scramble Sport/Dport/Pid on the bpfeb
+	// (big-endian) build.
+	if err := binary.Read(bytes.NewReader(raw), binary.NativeEndian, &tuple); err != nil {
+		return fmt.Errorf("failed to decode flow_tuple sample: %w", err)
+	}
 
-// GetStats fetches stats of BPF program.
-func (t *TracerStreaming) GetStats() (map[int]*BpfProgramStats, error) {
-	return getBPFAllStats(t.obj.obj)
+	saddr := inetNtop(tuple.Family, tuple.Saddr)
+	daddr := inetNtop(tuple.Family, tuple.Daddr)
+	flow := &Flow{
+		SAddr:   &saddr,
+		DAddr:   &daddr,
+		LPort:   tuple.Sport,
+		L4Proto: tuple.L4Proto,
+		LastPID: tuple.Pid,
+	}
+
+	for _, e := range t.exporters {
+		if err := e.ExportFlow(context.Background(), flow); err != nil {
+			log.Println(err)
+		}
+	}
+
+	if globalFlowChan != nil {
+		globalFlowChan <- flow
+	}
+	return nil
 }
@@ -0,0 +1,68 @@
+//go:build linux
+
+package conntracer
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/cilium/ebpf"
+)
+
+// udpBound marks a port as currently bound by a UDP listener in
+// udp_port_binding; the value itself is never read back by Go, only by
+// the BPF programs.
+const udpBound uint8 = 1
+
+// initializeUDPPortBindingMap seeds udp_port_binding from the kernel's
+// current view of bound UDP sockets (/proc/net/udp and udp6) so that
+// udp_sendmsg can tell active opens from passive ones for connections
+// that were already established before the tracer attached.
+func initializeUDPPortBindingMap(m *ebpf.Map) error {
+	for _, path := range []string{"/proc/net/udp", "/proc/net/udp6"} {
+		ports, err := boundUDPPorts(path)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		for _, port := range ports {
+			if err := m.Put(port, udpBound); err != nil {
+				return fmt.Errorf("failed to update udp_port_binding map: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+func boundUDPPorts(path string) ([]uint16, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var ports []uint16
+	scanner := bufio.NewScanner(f)
+	scanner.Scan() // skip header
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		localAddr := strings.SplitN(fields[1], ":", 2)
+		if len(localAddr) != 2 {
+			continue
+		}
+		port, err := strconv.ParseUint(localAddr[1], 16, 16)
+		if err != nil {
+			continue
+		}
+		ports = append(ports, uint16(port))
+	}
+	return ports, scanner.Err()
+}